@@ -0,0 +1,74 @@
+package tfexec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// BackendMode describes whether a plan is produced by the traditional
+// "write a local plan file" workflow, or by a remote run against a
+// Terraform Cloud `cloud {}` / `remote` backend, which performs the plan
+// server-side and has no local plan file to read back.
+type BackendMode int
+
+const (
+	// BackendModeLocal is the traditional workflow: `terraform plan
+	// -out=<file>` writes a plan file tfexec reads back from disk.
+	BackendModeLocal BackendMode = iota
+	// BackendModeRemote means the plan runs as a remote operation; there is
+	// no local plan file, and the run must be re-applied remotely rather
+	// than from a local artifact.
+	BackendModeRemote
+)
+
+// backendStateFile is the subset of .terraform/terraform.tfstate tfmigrate
+// cares about. Terraform writes this file during `terraform init` to
+// record which backend the working directory is configured to use; it's
+// not the actual state (which may live remotely), just the backend
+// pointer.
+type backendStateFile struct {
+	Backend struct {
+		Type string `json:"type"`
+	} `json:"backend"`
+}
+
+// remoteBackendTypes are the backend `type` values in
+// .terraform/terraform.tfstate that perform plan/apply as a remote
+// operation instead of running locally against local or locally-locked
+// remote state.
+var remoteBackendTypes = map[string]bool{
+	"remote": true,
+	"cloud":  true,
+}
+
+// DetectBackendMode inspects .terraform/terraform.tfstate in the working
+// directory to determine whether the configured backend is a local
+// workflow or a remote-run backend, proactively and without having to run
+// a command and react to its failure.
+func (c *terraformCLI) DetectBackendMode(ctx context.Context) (BackendMode, error) {
+	path := filepath.Join(c.Dir(), ".terraform", "terraform.tfstate")
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Not yet initialized, or using the default local backend
+			// (which some Terraform versions don't bother writing a
+			// backend pointer for).
+			return BackendModeLocal, nil
+		}
+		return BackendModeLocal, fmt.Errorf("failed to read backend config %s: %s", path, err)
+	}
+
+	var f backendStateFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return BackendModeLocal, fmt.Errorf("failed to parse backend config %s: %s", path, err)
+	}
+
+	if remoteBackendTypes[f.Backend.Type] {
+		return BackendModeRemote, nil
+	}
+	return BackendModeLocal, nil
+}
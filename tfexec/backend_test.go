@@ -0,0 +1,147 @@
+package tfexec
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTerraformCLIDetectBackendMode(t *testing.T) {
+	cases := []struct {
+		desc         string
+		backendState string // contents of .terraform/terraform.tfstate; "" means don't write the file
+		want         BackendMode
+	}{
+		{
+			desc:         "no .terraform/terraform.tfstate (not initialized, or default local backend)",
+			backendState: "",
+			want:         BackendModeLocal,
+		},
+		{
+			desc:         "local backend",
+			backendState: `{"backend":{"type":"local"}}`,
+			want:         BackendModeLocal,
+		},
+		{
+			desc:         "s3 backend",
+			backendState: `{"backend":{"type":"s3"}}`,
+			want:         BackendModeLocal,
+		},
+		{
+			desc:         "remote backend",
+			backendState: `{"backend":{"type":"remote"}}`,
+			want:         BackendModeRemote,
+		},
+		{
+			desc:         "cloud backend",
+			backendState: `{"backend":{"type":"cloud"}}`,
+			want:         BackendModeRemote,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			if tc.backendState != "" {
+				if err := os.MkdirAll(filepath.Join(dir, ".terraform"), 0755); err != nil {
+					t.Fatalf("failed to create .terraform dir: %s", err)
+				}
+				path := filepath.Join(dir, ".terraform", "terraform.tfstate")
+				if err := os.WriteFile(path, []byte(tc.backendState), 0600); err != nil {
+					t.Fatalf("failed to write backend state: %s", err)
+				}
+			}
+
+			terraformCLI := NewTerraformCLI(&fakeExecutor{dir: dir})
+
+			got, err := terraformCLI.DetectBackendMode(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if got != tc.want {
+				t.Errorf("got: %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// remoteBackendDir creates a temp working directory whose
+// .terraform/terraform.tfstate marks it as using a remote/cloud backend,
+// so DetectBackendMode reports BackendModeRemote for it.
+func remoteBackendDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, ".terraform"), 0755); err != nil {
+		t.Fatalf("failed to create .terraform dir: %s", err)
+	}
+	backendState := filepath.Join(dir, ".terraform", "terraform.tfstate")
+	if err := os.WriteFile(backendState, []byte(`{"backend":{"type":"remote"}}`), 0600); err != nil {
+		t.Fatalf("failed to write backend state: %s", err)
+	}
+	return dir
+}
+
+func TestTerraformCLIPlanAgainstRemoteBackend(t *testing.T) {
+	dir := remoteBackendDir(t)
+
+	e := &fakeExecutor{
+		dir: dir,
+		runFunc: func(args ...string) (string, string, error) {
+			for _, a := range args {
+				if a == "plan" {
+					return "Running plan in Terraform Cloud. Output will stream here...\n", "", nil
+				}
+			}
+			// terraform show -json (no plan file, since a remote run never
+			// wrote one locally)
+			return `{"format_version":"1.1","resource_changes":[{"address":"null_resource.foo","change":{"actions":["create"]}}],"output_changes":{}}`, "", nil
+		},
+	}
+	terraformCLI := NewTerraformCLI(e)
+
+	plan, err := terraformCLI.Plan(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	if plan == nil {
+		t.Fatal("plan success but returns nil")
+	}
+}
+
+// TestTerraformCLIPlanStripsOutOptionAgainstRemoteBackend proves a
+// caller-supplied -out= doesn't reach a remote run: if it did, terraform
+// plan would reject it with "Terraform Cloud does not support saving the
+// generated execution plan locally", the exact failure DetectBackendMode
+// exists to avoid.
+func TestTerraformCLIPlanStripsOutOptionAgainstRemoteBackend(t *testing.T) {
+	dir := remoteBackendDir(t)
+
+	e := &fakeExecutor{
+		dir: dir,
+		runFunc: func(args ...string) (string, string, error) {
+			for _, a := range args {
+				if a == "plan" {
+					for _, a := range args {
+						if strings.HasPrefix(a, "-out=") {
+							return "", "", fmt.Errorf("Terraform Cloud does not support saving the generated execution plan locally")
+						}
+					}
+					return "Running plan in Terraform Cloud. Output will stream here...\n", "", nil
+				}
+			}
+			return `{"format_version":"1.1","resource_changes":[],"output_changes":{}}`, "", nil
+		},
+	}
+	terraformCLI := NewTerraformCLI(e)
+
+	plan, err := terraformCLI.Plan(context.Background(), nil, "-out=/path/to/planfile")
+	if err != nil {
+		t.Fatalf("unexpected err: %s (a caller-supplied -out= should be stripped against a remote backend)", err)
+	}
+	if plan == nil {
+		t.Fatal("plan success but returns nil")
+	}
+}
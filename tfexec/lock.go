@@ -0,0 +1,198 @@
+package tfexec
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// LockHandle identifies a state lock previously acquired with Lock, so it
+// can be released again with Unlock.
+type LockHandle struct {
+	ID string
+}
+
+// Locker abstracts acquiring and releasing the state lock for a backend.
+// Relying on each `terraform` subprocess to grab and release the lock
+// independently leaves a window between a Plan and the state-manipulation
+// commands that follow it where another actor can mutate state; a Locker
+// lets a whole migration hold a single lock across that window instead.
+type Locker interface {
+	// Lock acquires the state lock and returns a handle identifying it.
+	Lock(ctx context.Context) (LockHandle, error)
+	// Unlock releases a lock previously returned by Lock.
+	Unlock(ctx context.Context, handle LockHandle) error
+}
+
+// Renewer is an optional capability a Locker can implement to extend a
+// held lock's lease instead of letting it expire partway through a
+// long-running migration. WithLock calls Renew periodically for as long
+// as its fn is running, if the Locker it was given implements Renewer.
+type Renewer interface {
+	Renew(ctx context.Context, handle LockHandle) (LockHandle, error)
+}
+
+// Lock acquires the state lock for the working directory via locker.
+func (c *terraformCLI) Lock(ctx context.Context, locker Locker) (LockHandle, error) {
+	if locker == nil {
+		return LockHandle{}, fmt.Errorf("failed to acquire state lock: no Locker configured")
+	}
+	return locker.Lock(ctx)
+}
+
+// Unlock releases a lock previously acquired with Lock.
+func (c *terraformCLI) Unlock(ctx context.Context, locker Locker, handle LockHandle) error {
+	if locker == nil {
+		return fmt.Errorf("failed to release state lock: no Locker configured")
+	}
+	return locker.Unlock(ctx, handle)
+}
+
+// WithLock acquires the state lock via locker, runs fn, and always
+// releases the lock afterwards, even if fn returns an error. This lets an
+// entire multi-step migration (several `state mv`, an `import`, and a
+// final verification Plan) hold a single lock with one acquisition
+// instead of one per subprocess.
+//
+// If locker implements Renewer, the lease is renewed every renewInterval
+// for as long as fn is running, so the lock doesn't expire out from under
+// a migration that takes longer than the backend's default lease. Pass
+// renewInterval <= 0 (or a Locker that doesn't implement Renewer) to skip
+// renewal.
+func (c *terraformCLI) WithLock(ctx context.Context, locker Locker, renewInterval time.Duration, fn func(ctx context.Context) error) error {
+	handle, err := c.Lock(ctx, locker)
+	if err != nil {
+		return err
+	}
+	lease := &lockLease{handle: handle}
+
+	if renewer, ok := locker.(Renewer); ok && renewInterval > 0 {
+		renewCtx, stopRenewing := context.WithCancel(ctx)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lease.renewPeriodically(renewCtx, renewer, renewInterval)
+		}()
+
+		defer func() {
+			// Stop renewing and wait for the goroutine to exit before
+			// unlocking with the latest handle it may have produced.
+			stopRenewing()
+			wg.Wait()
+			_ = c.Unlock(ctx, locker, lease.get())
+		}()
+	} else {
+		defer func() {
+			_ = c.Unlock(ctx, locker, lease.get())
+		}()
+	}
+
+	return fn(ctx)
+}
+
+// lockLease holds the current LockHandle for a lock being renewed in the
+// background, guarded by a mutex since the renewal goroutine and WithLock
+// both read/write it.
+type lockLease struct {
+	mu     sync.Mutex
+	handle LockHandle
+}
+
+func (l *lockLease) get() LockHandle {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.handle
+}
+
+func (l *lockLease) set(h LockHandle) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.handle = h
+}
+
+// renewPeriodically calls renewer.Renew every interval until ctx is
+// canceled. A failed renewal is not fatal (the lock may still be held):
+// its error is dropped, the previous handle is kept, and renewal is tried
+// again at the next tick. Nothing reports a failed renewal to the caller.
+func (l *lockLease) renewPeriodically(ctx context.Context, renewer Renewer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if renewed, err := renewer.Renew(ctx, l.get()); err == nil {
+				l.set(renewed)
+			}
+		}
+	}
+}
+
+// LocalFileLocker is a Locker for the local backend, backed by a plain
+// lock file created with O_EXCL so that only one holder can create it at
+// a time. Path should be the same path every caller coordinating on this
+// lock uses, typically next to the local backend's state file.
+type LocalFileLocker struct {
+	Path string
+}
+
+// Lock creates the lock file, failing if it already exists.
+func (l *LocalFileLocker) Lock(ctx context.Context) (LockHandle, error) {
+	f, err := os.OpenFile(l.Path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		if os.IsExist(err) {
+			return LockHandle{}, fmt.Errorf("failed to acquire state lock: %s is already locked", l.Path)
+		}
+		return LockHandle{}, fmt.Errorf("failed to acquire state lock: %s", err)
+	}
+	defer f.Close()
+
+	return LockHandle{ID: l.Path}, nil
+}
+
+// Unlock removes the lock file.
+func (l *LocalFileLocker) Unlock(ctx context.Context, handle LockHandle) error {
+	if err := os.Remove(handle.ID); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to release state lock: %s", err)
+	}
+	return nil
+}
+
+// Renew touches the lock file's modification time, extending its lease
+// for any external reaper that treats a stale mtime as an abandoned lock.
+func (l *LocalFileLocker) Renew(ctx context.Context, handle LockHandle) (LockHandle, error) {
+	now := time.Now()
+	if err := os.Chtimes(handle.ID, now, now); err != nil {
+		return handle, fmt.Errorf("failed to renew state lock: %s", err)
+	}
+	return handle, nil
+}
+
+// ForceUnlockLocker releases a lock by running `terraform force-unlock`.
+// It can't acquire a lock on its own (only a running `terraform` command
+// actually takes one), so Lock always fails; it's meant to unblock a
+// migration stuck behind a stale lock, given a lock ID obtained out of
+// band (e.g. the "Lock Info" a failed `terraform plan` prints), not as a
+// general-purpose Locker.
+type ForceUnlockLocker struct {
+	CLI *terraformCLI
+}
+
+// Lock always returns an error; see the ForceUnlockLocker doc comment.
+func (l *ForceUnlockLocker) Lock(ctx context.Context) (LockHandle, error) {
+	return LockHandle{}, fmt.Errorf("ForceUnlockLocker cannot acquire a lock; use LocalFileLocker, a backend-specific Locker, or acquire the lock out of band")
+}
+
+// Unlock runs `terraform force-unlock -force <handle.ID>`.
+func (l *ForceUnlockLocker) Unlock(ctx context.Context, handle LockHandle) error {
+	_, _, err := l.CLI.Run(ctx, "force-unlock", "-force", handle.ID)
+	if err != nil {
+		return fmt.Errorf("failed to run terraform force-unlock: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package tfexec
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeLocker struct {
+	lockCalls   int
+	unlockCalls int
+	lockErr     error
+	unlockErr   error
+	handle      LockHandle
+}
+
+func (f *fakeLocker) Lock(ctx context.Context) (LockHandle, error) {
+	f.lockCalls++
+	if f.lockErr != nil {
+		return LockHandle{}, f.lockErr
+	}
+	return f.handle, nil
+}
+
+func (f *fakeLocker) Unlock(ctx context.Context, handle LockHandle) error {
+	f.unlockCalls++
+	return f.unlockErr
+}
+
+func TestTerraformCLIWithLockRunsFnWhileLocked(t *testing.T) {
+	locker := &fakeLocker{handle: LockHandle{ID: "lock-1"}}
+	terraformCLI := NewTerraformCLI(&fakeExecutor{})
+
+	var ranWithHandle bool
+	err := terraformCLI.WithLock(context.Background(), locker, 0, func(ctx context.Context) error {
+		ranWithHandle = locker.lockCalls == 1 && locker.unlockCalls == 0
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	if !ranWithHandle {
+		t.Error("fn did not run while the lock was held")
+	}
+	if locker.unlockCalls != 1 {
+		t.Errorf("got %d unlock calls, want 1", locker.unlockCalls)
+	}
+}
+
+func TestTerraformCLIWithLockUnlocksOnFnError(t *testing.T) {
+	locker := &fakeLocker{handle: LockHandle{ID: "lock-1"}}
+	terraformCLI := NewTerraformCLI(&fakeExecutor{})
+
+	wantErr := errors.New("migration step failed")
+	err := terraformCLI.WithLock(context.Background(), locker, 0, func(ctx context.Context) error {
+		return wantErr
+	})
+	if err != wantErr {
+		t.Errorf("got err: %v, want: %v", err, wantErr)
+	}
+	if locker.unlockCalls != 1 {
+		t.Errorf("got %d unlock calls, want 1 (lock must be released even when fn fails)", locker.unlockCalls)
+	}
+}
+
+func TestTerraformCLIWithLockFailsWhenLockCannotBeAcquired(t *testing.T) {
+	wantErr := errors.New("lock held by another process")
+	locker := &fakeLocker{lockErr: wantErr}
+	terraformCLI := NewTerraformCLI(&fakeExecutor{})
+
+	called := false
+	err := terraformCLI.WithLock(context.Background(), locker, 0, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	if err != wantErr {
+		t.Errorf("got err: %v, want: %v", err, wantErr)
+	}
+	if called {
+		t.Error("fn should not run when the lock can't be acquired")
+	}
+	if locker.unlockCalls != 0 {
+		t.Errorf("got %d unlock calls, want 0", locker.unlockCalls)
+	}
+}
+
+// renewingLocker is a fakeLocker that also implements Renewer, so
+// WithLock's renewal goroutine exercises it.
+type renewingLocker struct {
+	fakeLocker
+	renewCalls int32
+}
+
+func (r *renewingLocker) Renew(ctx context.Context, handle LockHandle) (LockHandle, error) {
+	atomic.AddInt32(&r.renewCalls, 1)
+	return handle, nil
+}
+
+func TestTerraformCLIWithLockRenewsPeriodically(t *testing.T) {
+	locker := &renewingLocker{fakeLocker: fakeLocker{handle: LockHandle{ID: "lock-1"}}}
+	terraformCLI := NewTerraformCLI(&fakeExecutor{})
+
+	err := terraformCLI.WithLock(context.Background(), locker, 10*time.Millisecond, func(ctx context.Context) error {
+		time.Sleep(55 * time.Millisecond)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	if got := atomic.LoadInt32(&locker.renewCalls); got < 2 {
+		t.Errorf("got %d renew calls, want at least 2 over a 55ms hold with a 10ms interval", got)
+	}
+	if locker.unlockCalls != 1 {
+		t.Errorf("got %d unlock calls, want 1", locker.unlockCalls)
+	}
+}
+
+func TestTerraformCLIWithLockStopsRenewingAfterFnReturns(t *testing.T) {
+	locker := &renewingLocker{fakeLocker: fakeLocker{handle: LockHandle{ID: "lock-1"}}}
+	terraformCLI := NewTerraformCLI(&fakeExecutor{})
+
+	if err := terraformCLI.WithLock(context.Background(), locker, 5*time.Millisecond, func(ctx context.Context) error {
+		return nil
+	}); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	after := atomic.LoadInt32(&locker.renewCalls)
+	time.Sleep(30 * time.Millisecond)
+	if got := atomic.LoadInt32(&locker.renewCalls); got != after {
+		t.Errorf("renew was called %d more time(s) after WithLock returned", got-after)
+	}
+}
+
+// TestLocalFileLockerBlocksASecondLockWhileAMigrationIsInProgress is an
+// integration test (no mocks) exercising WithLock end to end: a second
+// attempt to acquire the same lock must fail while the first holder's
+// migration is still running.
+func TestLocalFileLockerBlocksASecondLockWhileAMigrationIsInProgress(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "terraform.tfstate.lock")
+	locker := &LocalFileLocker{Path: lockPath}
+	terraformCLI := NewTerraformCLI(&fakeExecutor{})
+
+	migrationStarted := make(chan struct{})
+	migrationMayFinish := make(chan struct{})
+	migrationErr := make(chan error, 1)
+
+	go func() {
+		migrationErr <- terraformCLI.WithLock(context.Background(), locker, 0, func(ctx context.Context) error {
+			close(migrationStarted)
+			<-migrationMayFinish
+			return nil
+		})
+	}()
+
+	<-migrationStarted
+
+	second := &LocalFileLocker{Path: lockPath}
+	if _, err := second.Lock(context.Background()); err == nil {
+		t.Error("expected a second lock attempt to fail while the migration holds the lock")
+	}
+
+	close(migrationMayFinish)
+	if err := <-migrationErr; err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	// Once the migration released the lock, acquiring it again succeeds.
+	if _, err := second.Lock(context.Background()); err != nil {
+		t.Errorf("expected the lock to be acquirable after release, got: %s", err)
+	}
+}
@@ -0,0 +1,149 @@
+package tfexec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// PlanJSON is a subset of the plan representation produced by
+// `terraform show -json <planfile>`. It only decodes the fields needed
+// to classify the kind of changes a plan contains; the full schema has
+// many more fields (planned_values, configuration, variables, etc.)
+// that tfmigrate doesn't need today.
+type PlanJSON struct {
+	FormatVersion   string                `json:"format_version"`
+	ResourceChanges []PlanResourceChange  `json:"resource_changes"`
+	OutputChanges   map[string]PlanChange `json:"output_changes"`
+	// ResourceDrift lists differences the pre-plan refresh found between
+	// state and real infrastructure. These aren't changes this plan makes;
+	// PlanPolicy.AllowNoopRefresh controls whether they're allowed to pass
+	// through unexamined.
+	ResourceDrift []PlanResourceChange `json:"resource_drift"`
+}
+
+// PlanResourceChange is a single entry of the plan JSON's
+// resource_changes array.
+type PlanResourceChange struct {
+	Address string     `json:"address"`
+	Change  PlanChange `json:"change"`
+}
+
+// PlanChange is the change envelope shared by resource_changes and
+// output_changes entries in the plan JSON schema. Before and After are
+// kept only when they are JSON objects, as a resource's values are, so a
+// PlanPolicy can tell which attributes actually changed; an output's
+// value of any other type leaves them nil.
+type PlanChange struct {
+	Actions []string               `json:"actions"`
+	Before  map[string]interface{} `json:"before"`
+	After   map[string]interface{} `json:"after"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler. A before/after value that
+// isn't a JSON object is skipped instead of failing the whole decode.
+func (c *PlanChange) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Actions []string        `json:"actions"`
+		Before  json.RawMessage `json:"before"`
+		After   json.RawMessage `json:"after"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	c.Actions, c.Before, c.After = raw.Actions, nil, nil
+	_ = json.Unmarshal(raw.Before, &c.Before)
+	_ = json.Unmarshal(raw.After, &c.After)
+	return nil
+}
+
+// Plan JSON change actions, as defined by the plan-JSON schema.
+// https://developer.hashicorp.com/terraform/internals/json-format#change-representation
+const (
+	ActionNoOp   = "no-op"
+	ActionCreate = "create"
+	ActionRead   = "read"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// PlanChangeClass summarizes the kind of changes a plan contains, so
+// that an ignoreOutputDiffs-style policy can be driven off a structured
+// classification instead of grepping the plan's human-readable stdout.
+type PlanChangeClass int
+
+const (
+	// PlanChangeNone means the plan is a complete no-op.
+	PlanChangeNone PlanChangeClass = iota
+	// PlanChangeOutputOnly means only output values change; no resource
+	// is created, updated, deleted or replaced.
+	PlanChangeOutputOnly
+	// PlanChangeResource means at least one resource change has an
+	// action other than no-op: create, update, delete, replace, or read.
+	PlanChangeResource
+)
+
+// String implements fmt.Stringer.
+func (c PlanChangeClass) String() string {
+	switch c {
+	case PlanChangeNone:
+		return "no changes"
+	case PlanChangeOutputOnly:
+		return "output changes only"
+	case PlanChangeResource:
+		return "resource changes"
+	default:
+		return "unknown plan change class"
+	}
+}
+
+// ShowPlanJSON runs `terraform show -json` and decodes the result. If
+// planFile is non-empty, it shows that saved plan file; otherwise it
+// shows the latest plan for the working directory, which is how a
+// cloud/remote backend's server-side run is inspected after the fact,
+// since there is no local plan file to read back in that case. It
+// requires Terraform 0.12 or later.
+func (c *terraformCLI) ShowPlanJSON(ctx context.Context, planFile string) (*PlanJSON, error) {
+	args := []string{"show", "-json"}
+	if planFile != "" {
+		args = append(args, planFile)
+	}
+
+	out, _, err := c.Run(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run terraform show -json: %s", err)
+	}
+
+	var p PlanJSON
+	if err := json.Unmarshal([]byte(out), &p); err != nil {
+		return nil, fmt.Errorf("failed to parse terraform show -json output: %s", err)
+	}
+
+	return &p, nil
+}
+
+// ClassifyPlanJSON walks resource_changes and output_changes to
+// determine whether a plan is a no-op, touches only outputs, or has a
+// resource change with any action other than no-op (create, update,
+// delete or replace, and also a data source read).
+func ClassifyPlanJSON(p *PlanJSON) PlanChangeClass {
+	for _, rc := range p.ResourceChanges {
+		if !isNoOpAction(rc.Change.Actions) {
+			return PlanChangeResource
+		}
+	}
+
+	for _, oc := range p.OutputChanges {
+		if !isNoOpAction(oc.Actions) {
+			return PlanChangeOutputOnly
+		}
+	}
+
+	return PlanChangeNone
+}
+
+// isNoOpAction reports whether a change's actions represent a no-op.
+// Terraform encodes a no-op as an actions list of exactly ["no-op"].
+func isNoOpAction(actions []string) bool {
+	return len(actions) == 0 || (len(actions) == 1 && actions[0] == ActionNoOp)
+}
@@ -0,0 +1,155 @@
+package tfexec
+
+import (
+	"context"
+	"regexp"
+	"testing"
+)
+
+func TestClassifyPlanJSON(t *testing.T) {
+	cases := []struct {
+		desc string
+		plan *PlanJSON
+		want PlanChangeClass
+	}{
+		{
+			desc: "no changes",
+			plan: &PlanJSON{
+				ResourceChanges: []PlanResourceChange{
+					{Address: "null_resource.foo", Change: PlanChange{Actions: []string{ActionNoOp}}},
+				},
+				OutputChanges: map[string]PlanChange{
+					"test": {Actions: []string{ActionNoOp}},
+				},
+			},
+			want: PlanChangeNone,
+		},
+		{
+			desc: "output changes only",
+			plan: &PlanJSON{
+				ResourceChanges: []PlanResourceChange{
+					{Address: "null_resource.foo", Change: PlanChange{Actions: []string{ActionNoOp}}},
+				},
+				OutputChanges: map[string]PlanChange{
+					"test": {Actions: []string{ActionUpdate}},
+				},
+			},
+			want: PlanChangeOutputOnly,
+		},
+		{
+			desc: "resource create",
+			plan: &PlanJSON{
+				ResourceChanges: []PlanResourceChange{
+					{Address: "null_resource.foo", Change: PlanChange{Actions: []string{ActionCreate}}},
+				},
+			},
+			want: PlanChangeResource,
+		},
+		{
+			desc: "resource update",
+			plan: &PlanJSON{
+				ResourceChanges: []PlanResourceChange{
+					{Address: "null_resource.foo", Change: PlanChange{Actions: []string{ActionUpdate}}},
+				},
+			},
+			want: PlanChangeResource,
+		},
+		{
+			desc: "resource delete",
+			plan: &PlanJSON{
+				ResourceChanges: []PlanResourceChange{
+					{Address: "null_resource.foo", Change: PlanChange{Actions: []string{ActionDelete}}},
+				},
+			},
+			want: PlanChangeResource,
+		},
+		{
+			desc: "resource replace",
+			plan: &PlanJSON{
+				ResourceChanges: []PlanResourceChange{
+					{Address: "null_resource.foo", Change: PlanChange{Actions: []string{ActionDelete, ActionCreate}}},
+				},
+				OutputChanges: map[string]PlanChange{
+					"test": {Actions: []string{ActionUpdate}},
+				},
+			},
+			want: PlanChangeResource,
+		},
+		{
+			desc: "no resource_changes or output_changes at all",
+			plan: &PlanJSON{},
+			want: PlanChangeNone,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := ClassifyPlanJSON(tc.plan)
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTerraformCLIShowPlanJSON(t *testing.T) {
+	cases := []struct {
+		desc     string
+		stdout   string
+		exitCode int
+		want     *PlanJSON
+		ok       bool
+	}{
+		{
+			desc:     "resource create",
+			stdout:   `{"format_version":"1.1","resource_changes":[{"address":"null_resource.foo","change":{"actions":["create"]}}],"output_changes":{}}`,
+			exitCode: 0,
+			want: &PlanJSON{
+				FormatVersion: "1.1",
+				ResourceChanges: []PlanResourceChange{
+					{Address: "null_resource.foo", Change: PlanChange{Actions: []string{ActionCreate}}},
+				},
+				OutputChanges: map[string]PlanChange{},
+			},
+			ok: true,
+		},
+		{
+			desc:     "terraform show -json failed (e.g. too old to support it)",
+			stdout:   "",
+			exitCode: 1,
+			want:     nil,
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			mockCommands := []*mockCommand{
+				{
+					args:     []string{"terraform", "show", "-json", "/path/to/planfile"},
+					argsRe:   regexp.MustCompile(`^terraform show -json .+$`),
+					stdout:   tc.stdout,
+					exitCode: tc.exitCode,
+				},
+			}
+			e := NewMockExecutor(mockCommands)
+			terraformCLI := NewTerraformCLI(e)
+
+			got, err := terraformCLI.ShowPlanJSON(context.Background(), "/path/to/planfile")
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok {
+				if got.FormatVersion != tc.want.FormatVersion {
+					t.Errorf("got format_version: %s, want: %s", got.FormatVersion, tc.want.FormatVersion)
+				}
+				if len(got.ResourceChanges) != len(tc.want.ResourceChanges) {
+					t.Errorf("got %d resource_changes, want %d", len(got.ResourceChanges), len(tc.want.ResourceChanges))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package tfexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestEvaluatePlanPolicy(t *testing.T) {
+	cases := []struct {
+		desc          string
+		policy        PlanPolicy
+		plan          *PlanJSON
+		wantResources []string
+		wantOutputs   []string
+	}{
+		{
+			desc:   "no-op plan is always fine",
+			policy: PlanPolicy{},
+			plan: &PlanJSON{
+				ResourceChanges: []PlanResourceChange{
+					{Address: "null_resource.foo", Change: PlanChange{Actions: []string{ActionNoOp}}},
+				},
+				OutputChanges: map[string]PlanChange{
+					"test": {Actions: []string{ActionNoOp}},
+				},
+			},
+		},
+		{
+			desc:   "output change rejected by default",
+			policy: PlanPolicy{},
+			plan: &PlanJSON{
+				OutputChanges: map[string]PlanChange{
+					"test": {Actions: []string{ActionUpdate}},
+				},
+			},
+			wantOutputs: []string{"test"},
+		},
+		{
+			desc:   "output change allowed by AllowOutputChanges",
+			policy: PlanPolicy{AllowOutputChanges: true},
+			plan: &PlanJSON{
+				OutputChanges: map[string]PlanChange{
+					"test": {Actions: []string{ActionUpdate}},
+				},
+			},
+		},
+		{
+			desc:   "resource change rejected by default",
+			policy: PlanPolicy{},
+			plan: &PlanJSON{
+				ResourceChanges: []PlanResourceChange{
+					{Address: "aws_iam_role.example", Change: PlanChange{Actions: []string{ActionUpdate}}},
+				},
+			},
+			wantResources: []string{"aws_iam_role.example"},
+		},
+		{
+			desc: "resource change allowed by exact address",
+			policy: PlanPolicy{
+				AllowResourceChanges: []string{"aws_iam_role.example"},
+			},
+			plan: &PlanJSON{
+				ResourceChanges: []PlanResourceChange{
+					{Address: "aws_iam_role.example", Change: PlanChange{Actions: []string{ActionUpdate}}},
+				},
+			},
+		},
+		{
+			desc: "attribute change allowed by wildcard pattern",
+			policy: PlanPolicy{
+				AllowAttributeChanges: map[string][]string{
+					"aws_instance.*": {"tags"},
+				},
+			},
+			plan: &PlanJSON{
+				ResourceChanges: []PlanResourceChange{
+					{
+						Address: "aws_instance.foo",
+						Change: PlanChange{
+							Actions: []string{ActionUpdate},
+							Before:  map[string]interface{}{"tags": map[string]interface{}{"LastSeen": "a"}, "ami": "ami-1"},
+							After:   map[string]interface{}{"tags": map[string]interface{}{"LastSeen": "b"}, "ami": "ami-1"},
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "attribute change rejected when an unlisted attribute also changed",
+			policy: PlanPolicy{
+				AllowAttributeChanges: map[string][]string{
+					"aws_instance.*": {"tags"},
+				},
+			},
+			plan: &PlanJSON{
+				ResourceChanges: []PlanResourceChange{
+					{
+						Address: "aws_instance.foo",
+						Change: PlanChange{
+							Actions: []string{ActionUpdate},
+							Before:  map[string]interface{}{"tags": map[string]interface{}{"LastSeen": "a"}, "ami": "ami-1"},
+							After:   map[string]interface{}{"tags": map[string]interface{}{"LastSeen": "b"}, "ami": "ami-2"},
+						},
+					},
+				},
+			},
+			wantResources: []string{"aws_instance.foo"},
+		},
+		{
+			desc:   "noop-refresh drift allowed by AllowNoopRefresh",
+			policy: PlanPolicy{AllowNoopRefresh: true},
+			plan: &PlanJSON{
+				ResourceDrift: []PlanResourceChange{
+					{Address: "aws_instance.foo", Change: PlanChange{Actions: []string{ActionUpdate}}},
+				},
+			},
+		},
+		{
+			desc:   "refresh drift rejected by default",
+			policy: PlanPolicy{},
+			plan: &PlanJSON{
+				ResourceDrift: []PlanResourceChange{
+					{Address: "aws_instance.foo", Change: PlanChange{Actions: []string{ActionUpdate}}},
+				},
+			},
+			wantResources: []string{"aws_instance.foo"},
+		},
+		{
+			desc: "drift for a resource with its own planned change is not a separate violation",
+			policy: PlanPolicy{
+				AllowResourceChanges: []string{"aws_instance.foo"},
+			},
+			plan: &PlanJSON{
+				ResourceChanges: []PlanResourceChange{
+					{Address: "aws_instance.foo", Change: PlanChange{Actions: []string{ActionUpdate}}},
+				},
+				ResourceDrift: []PlanResourceChange{
+					{Address: "aws_instance.foo", Change: PlanChange{Actions: []string{ActionUpdate}}},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := EvaluatePlanPolicy(tc.policy, tc.plan)
+
+			if len(tc.wantResources) == 0 && len(tc.wantOutputs) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected violation: %s", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("expected a policy violation, got nil")
+			}
+			violation, ok := err.(*PlanViolation)
+			if !ok {
+				t.Fatalf("got err of type %T, want *PlanViolation", err)
+			}
+			if !stringSlicesEqual(violation.Resources, tc.wantResources) {
+				t.Errorf("got resources: %v, want: %v", violation.Resources, tc.wantResources)
+			}
+			if !stringSlicesEqual(violation.Outputs, tc.wantOutputs) {
+				t.Errorf("got outputs: %v, want: %v", violation.Outputs, tc.wantOutputs)
+			}
+		})
+	}
+}
+
+// TestTerraformCLIPlanAndVerifyAllowsAPolicyCoveredChangeDespiteDetailedExitcode
+// is the scenario from the request that motivated PlanAndVerify: a
+// -detailed-exitcode plan exits as if it failed whenever there's any
+// diff at all, allowed by policy or not. PlanAndVerify must not
+// short-circuit on that error before EvaluatePlanPolicy gets to decide.
+func TestTerraformCLIPlanAndVerifyAllowsAPolicyCoveredChangeDespiteDetailedExitcode(t *testing.T) {
+	e := &fakeExecutor{
+		runFunc: func(args ...string) (string, string, error) {
+			for _, a := range args {
+				if a == "plan" {
+					// terraform plan -detailed-exitcode exits 2 whenever
+					// there's a diff, regardless of whether it's allowed.
+					return "", "", errors.New("exit status 2")
+				}
+			}
+			return `{"format_version":"1.1","resource_changes":[{"address":"aws_iam_role.example","change":{"actions":["update"]}}],"output_changes":{}}`, "", nil
+		},
+	}
+	terraformCLI := NewTerraformCLI(e)
+
+	policy := PlanPolicy{AllowResourceChanges: []string{"aws_iam_role.example"}}
+
+	p, err := terraformCLI.PlanAndVerify(context.Background(), nil, policy, "-detailed-exitcode")
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	if p == nil {
+		t.Fatal("plan success but returns nil")
+	}
+}
+
+func TestTerraformCLIPlanAndVerifyRejectsADisallowedChange(t *testing.T) {
+	e := &fakeExecutor{
+		runFunc: func(args ...string) (string, string, error) {
+			for _, a := range args {
+				if a == "plan" {
+					return "", "", errors.New("exit status 2")
+				}
+			}
+			return `{"format_version":"1.1","resource_changes":[{"address":"aws_iam_role.other","change":{"actions":["update"]}}],"output_changes":{}}`, "", nil
+		},
+	}
+	terraformCLI := NewTerraformCLI(e)
+
+	policy := PlanPolicy{AllowResourceChanges: []string{"aws_iam_role.example"}}
+
+	_, err := terraformCLI.PlanAndVerify(context.Background(), nil, policy, "-detailed-exitcode")
+	if _, ok := err.(*PlanViolation); !ok {
+		t.Fatalf("got err of type %T, want *PlanViolation", err)
+	}
+}
+
+// TestTerraformCLIPlanAndVerifyAgainstRemoteBackend proves PlanAndVerify
+// detects a remote backend the same way Plan does: it must not force a
+// -out= that the backend would reject, and must classify via
+// ShowPlanJSON against the backend's latest run.
+func TestTerraformCLIPlanAndVerifyAgainstRemoteBackend(t *testing.T) {
+	dir := remoteBackendDir(t)
+
+	e := &fakeExecutor{
+		dir: dir,
+		runFunc: func(args ...string) (string, string, error) {
+			for _, a := range args {
+				if strings.HasPrefix(a, "-out=") {
+					return "", "", fmt.Errorf("Terraform Cloud does not support saving the generated execution plan locally")
+				}
+				if a == "plan" {
+					return "Running plan in Terraform Cloud. Output will stream here...\n", "", nil
+				}
+			}
+			return `{"format_version":"1.1","resource_changes":[{"address":"aws_iam_role.example","change":{"actions":["update"]}}],"output_changes":{}}`, "", nil
+		},
+	}
+	terraformCLI := NewTerraformCLI(e)
+
+	policy := PlanPolicy{AllowResourceChanges: []string{"aws_iam_role.example"}}
+
+	p, err := terraformCLI.PlanAndVerify(context.Background(), nil, policy)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	if p == nil {
+		t.Fatal("plan success but returns nil")
+	}
+}
+
+func stringSlicesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
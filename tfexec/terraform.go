@@ -0,0 +1,63 @@
+package tfexec
+
+import "context"
+
+// Executor runs a terraform subcommand in a working directory and
+// returns its stdout and stderr.
+type Executor interface {
+	// Dir returns the working directory terraform commands are run in.
+	Dir() string
+	// Run executes terraform with args and returns (stdout, stderr, err).
+	Run(ctx context.Context, args ...string) (string, string, error)
+}
+
+// terraformCLI is a terraform(1) client that runs commands via an
+// Executor.
+type terraformCLI struct {
+	Executor          Executor
+	ignoreOutputDiffs bool
+	view              View
+}
+
+// NewTerraformCLI creates a new terraformCLI instance, reporting
+// progress and diagnostics to the default HumanView, which has no writer
+// and so discards them, until SetView is called.
+func NewTerraformCLI(e Executor) *terraformCLI {
+	return &terraformCLI{
+		Executor: e,
+		view:     NewHumanView(nil),
+	}
+}
+
+// SetIgnoreOutputDiffs sets whether Plan treats a plan containing only
+// output changes as a no-op.
+func (c *terraformCLI) SetIgnoreOutputDiffs(ignore bool) {
+	c.ignoreOutputDiffs = ignore
+}
+
+// SetView replaces the View this client reports progress and
+// diagnostics to. Passing nil restores the default HumanView.
+func (c *terraformCLI) SetView(v View) {
+	if v == nil {
+		v = NewHumanView(nil)
+	}
+	c.view = v
+}
+
+// Dir returns the working directory terraform commands are run in.
+func (c *terraformCLI) Dir() string {
+	return c.Executor.Dir()
+}
+
+// Run executes a terraform subcommand via the underlying Executor and
+// reports its raw output to the configured View. Every exported method in
+// this package that shells out to terraform goes through Run, so a View
+// set via SetView/NewTerraformCLI sees all of them, not just Plan.
+func (c *terraformCLI) Run(ctx context.Context, args ...string) (string, string, error) {
+	stdout, stderr, err := c.Executor.Run(ctx, args...)
+	c.view.RawOutput("stdout", stdout)
+	if stderr != "" {
+		c.view.RawOutput("stderr", stderr)
+	}
+	return stdout, stderr, err
+}
@@ -16,6 +16,17 @@ const (
 // Plan computes expected changes.
 // If a state is given, use it for the input state.
 func (c *terraformCLI) Plan(ctx context.Context, state *State, opts ...string) (*Plan, error) {
+	c.view.PlanStarted(c.Dir())
+
+	// Detect the backend up front: a cloud/remote backend runs the plan
+	// server-side and can't save it to a local file, so -out= must be
+	// skipped rather than passed and left to fail.
+	mode, err := c.DetectBackendMode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	remote := mode == BackendModeRemote
+
 	args := []string{"plan"}
 
 	if state != nil {
@@ -32,10 +43,21 @@ func (c *terraformCLI) Plan(ctx context.Context, state *State, opts ...string) (
 
 	// To return a plan file as a return value, we always use an -out option and load it to memory.
 	// if the option exists just use it else create a temporary file.
+	// A remote backend can't save a plan locally at all, so -out= is left
+	// unset in that case and the returned Plan is empty; the run is only
+	// classified afterwards via ShowPlanJSON, no Plan is rebuilt from it.
 	planOut := ""
-	if hasPrefixOptions(opts, "-out=") {
+	switch {
+	case remote:
+		// planOut stays empty: -out= is deliberately omitted for remote
+		// runs. Strip one a caller passed in too, or it reaches Run
+		// unchanged and reproduces the exact "Terraform Cloud does not
+		// support saving the generated execution plan locally" failure
+		// DetectBackendMode exists to avoid.
+		opts = removeOption(opts, "-out=")
+	case hasPrefixOptions(opts, "-out="):
 		planOut = getOptionValue(opts, "-out=")
-	} else {
+	default:
 		tmpPlan, err := os.CreateTemp("", "tfplan")
 		if err != nil {
 			return nil, fmt.Errorf("failed to create temporary plan file: %s", err)
@@ -51,28 +73,120 @@ func (c *terraformCLI) Plan(ctx context.Context, state *State, opts ...string) (
 
 	args = append(args, opts...)
 
-	out, _, err := c.Run(ctx, args...)
+	out, _, runErr := c.Run(ctx, args...)
 
 	// terraform plan -detailed-exitcode returns 2 if there is a diff.
 	// So we intentionally ignore an error of read the plan file and returns the
 	// original error of terraform plan command.
-	plan, _ := os.ReadFile(planOut)
-
-	// If ignore plan output changes is set to true and terraform plan option -detailed-exitcode, skip error code 2 and return plan with nil error
-	if err != nil && (c.ignoreOutputDiffs &&
-		strings.Contains(out, OutputChanges) &&
-		!strings.Contains(out, ChangesStartString) &&
-		!strings.Contains(out, ChangesEndString)) {
-		return NewPlan(plan), nil
+	var plan []byte
+	if planOut != "" {
+		plan, _ = os.ReadFile(planOut)
+	}
+
+	if remote {
+		// There is no local plan file to reconstruct a Plan from; ask the
+		// backend for the run it just performed instead.
+		class := c.classifyPlan(ctx, "", out)
+		c.view.PlanFinished(PlanSummary{Class: class, Empty: class == PlanChangeNone})
+		if runErr != nil && c.ignoreOutputDiffs && class != PlanChangeResource {
+			return NewPlan(plan), nil
+		}
+		return NewPlan(plan), runErr
+	}
+
+	// If ignoreOutputDiffs is set, a plan that contains no resource changes
+	// (i.e. it's a no-op or only touches outputs) is not treated as an error,
+	// even if terraform plan -detailed-exitcode exited 2.
+	if runErr != nil && c.ignoreOutputDiffs {
+		class := c.classifyPlan(ctx, planOut, out)
+		c.view.PlanFinished(PlanSummary{Class: class, Empty: class == PlanChangeNone})
+		if class != PlanChangeResource {
+			return NewPlan(plan), nil
+		}
+	}
+
+	return NewPlan(plan), runErr
+}
+
+// removeOption returns opts with every entry having the given prefix
+// dropped.
+func removeOption(opts []string, prefix string) []string {
+	filtered := make([]string, 0, len(opts))
+	for _, o := range opts {
+		if !strings.HasPrefix(o, prefix) {
+			filtered = append(filtered, o)
+		}
+	}
+	return filtered
+}
+
+// classifyPlan determines the kind of changes a plan contains. It prefers
+// decoding the structured `terraform show -json` output, which is robust
+// across Terraform versions and doesn't depend on -no-color being passed.
+// If that fails (e.g. a Terraform CLI too old to support `show -json` of a
+// saved plan file), it falls back to matching against the plan's
+// human-readable stdout, preserving the previous behavior.
+func (c *terraformCLI) classifyPlan(ctx context.Context, planFile string, stdout string) PlanChangeClass {
+	if pj, err := c.ShowPlanJSON(ctx, planFile); err == nil {
+		return ClassifyPlanJSON(pj)
+	}
+	return classifyPlanStdout(stdout)
+}
+
+// classifyPlanStdout is the fallback classifier used when `terraform show
+// -json` isn't available. It mirrors the historical stdout-grepping
+// behavior of Plan: only stdout that shows output changes and no resource
+// changes is PlanChangeOutputOnly. Anything else, including stdout with
+// none of the markers (such as that of a failed plan), is
+// PlanChangeResource, so Plan never ignores the error that came with it.
+func classifyPlanStdout(stdout string) PlanChangeClass {
+	if strings.Contains(stdout, OutputChanges) &&
+		!strings.Contains(stdout, ChangesStartString) &&
+		!strings.Contains(stdout, ChangesEndString) {
+		return PlanChangeOutputOnly
+	}
+	return PlanChangeResource
+}
+
+// Diff runs Plan and classifies the resulting plan, so that callers (such
+// as a migration's post-apply verification step) can assert that a plan is
+// state-only without re-implementing change classification themselves.
+//
+// Against a remote backend there is no local plan file to classify, the
+// same as Plan itself, so Diff checks DetectBackendMode rather than
+// unconditionally forcing a -out= that a remote run would reject.
+func (c *terraformCLI) Diff(ctx context.Context, state *State, opts ...string) (*Plan, PlanChangeClass, error) {
+	mode, err := c.DetectBackendMode(ctx)
+	if err != nil {
+		return nil, PlanChangeNone, err
+	}
+	remote := mode == BackendModeRemote
+
+	planOut := ""
+	planOpts := opts
+	if !remote {
+		tmpPlan, err := os.CreateTemp("", "tfplan")
+		if err != nil {
+			return nil, PlanChangeNone, fmt.Errorf("failed to create temporary plan file: %s", err)
+		}
+		planOut = tmpPlan.Name()
+		defer os.Remove(planOut)
+
+		if err := tmpPlan.Close(); err != nil {
+			return nil, PlanChangeNone, fmt.Errorf("failed to close temporary plan file: %s", err)
+		}
+		planOpts = append(append([]string{}, opts...), "-out="+planOut)
+	}
+
+	p, err := c.Plan(ctx, state, planOpts...)
+	if err != nil {
+		return p, PlanChangeResource, err
 	}
 
-	// If ignore plan output changes is set to true and only there are changes in outputs return plan and no error
-	if c.ignoreOutputDiffs &&
-		strings.Contains(out, OutputChanges) &&
-		!strings.Contains(out, ChangesStartString) &&
-		!strings.Contains(out, ChangesEndString) {
-		return NewPlan(plan), nil
+	pj, err := c.ShowPlanJSON(ctx, planOut)
+	if err != nil {
+		return p, PlanChangeNone, fmt.Errorf("failed to classify plan: %s", err)
 	}
 
-	return NewPlan(plan), err
+	return p, ClassifyPlanJSON(pj), nil
 }
@@ -126,6 +126,95 @@ func TestTerraformCLIPlan(t *testing.T) {
 	}
 }
 
+func TestTerraformCLIDiff(t *testing.T) {
+	cases := []struct {
+		desc          string
+		resourceJSON  string
+		wantOK        bool
+		wantPlanClass PlanChangeClass
+	}{
+		{
+			desc:          "no-op plan",
+			resourceJSON:  `{"address":"null_resource.foo","change":{"actions":["no-op"]}}`,
+			wantOK:        true,
+			wantPlanClass: PlanChangeNone,
+		},
+		{
+			desc:          "resource create",
+			resourceJSON:  `{"address":"null_resource.foo","change":{"actions":["create"]}}`,
+			wantOK:        true,
+			wantPlanClass: PlanChangeResource,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			plan := []byte("dummy plan")
+			e := &fakeExecutor{
+				runFunc: func(args ...string) (string, string, error) {
+					for _, a := range args {
+						if strings.HasPrefix(a, "-out=") {
+							planFile := a[len("-out="):]
+							if err := os.WriteFile(planFile, plan, 0600); err != nil {
+								return "", "", err
+							}
+							return "", "", nil
+						}
+					}
+					return fmt.Sprintf(`{"format_version":"1.1","resource_changes":[%s],"output_changes":{}}`, tc.resourceJSON), "", nil
+				},
+			}
+			terraformCLI := NewTerraformCLI(e)
+
+			got, class, err := terraformCLI.Diff(context.Background(), nil)
+			if tc.wantOK && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.wantOK && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if class != tc.wantPlanClass {
+				t.Errorf("got class: %s, want: %s", class, tc.wantPlanClass)
+			}
+			if tc.wantOK && !reflect.DeepEqual(got.Bytes(), plan) {
+				t.Errorf("got: %v, want: %v", got.Bytes(), plan)
+			}
+		})
+	}
+}
+
+// TestTerraformCLIDiffAgainstRemoteBackend proves Diff doesn't force a
+// -out= that a remote backend would reject: it must detect the backend
+// the same way Plan does and classify via ShowPlanJSON against the
+// backend's latest run instead of a local plan file.
+func TestTerraformCLIDiffAgainstRemoteBackend(t *testing.T) {
+	dir := remoteBackendDir(t)
+
+	e := &fakeExecutor{
+		dir: dir,
+		runFunc: func(args ...string) (string, string, error) {
+			for _, a := range args {
+				if strings.HasPrefix(a, "-out=") {
+					return "", "", fmt.Errorf("Terraform Cloud does not support saving the generated execution plan locally")
+				}
+				if a == "plan" {
+					return "Running plan in Terraform Cloud. Output will stream here...\n", "", nil
+				}
+			}
+			return `{"format_version":"1.1","resource_changes":[{"address":"null_resource.foo","change":{"actions":["create"]}}],"output_changes":{}}`, "", nil
+		},
+	}
+	terraformCLI := NewTerraformCLI(e)
+
+	_, class, err := terraformCLI.Diff(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	if class != PlanChangeResource {
+		t.Errorf("got class: %s, want: %s", class, PlanChangeResource)
+	}
+}
+
 func TestAccTerraformCLIPlan(t *testing.T) {
 	SkipUnlessAcceptanceTestEnabled(t)
 
@@ -0,0 +1,127 @@
+package tfexec
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// View decouples the narrow, stdout-grepping signal Plan used to rely on
+// from how progress and diagnostics are actually surfaced. Implementations
+// can forward to a terminal for humans, or emit structured events for a
+// tool (a dashboard, a PR bot) driving tfmigrate. It's a field on
+// terraformCLI (see NewTerraformCLI/SetView), and RawOutput fires for
+// every command run through Run, not just Plan.
+type View interface {
+	// PlanStarted is called right before `terraform plan` is run in dir.
+	PlanStarted(dir string)
+	// PlanFinished is called once a plan has been produced and classified.
+	PlanFinished(summary PlanSummary)
+	// Diagnostic surfaces a single diagnostic message encountered while
+	// running a command.
+	Diagnostic(d Diagnostic)
+	// RawOutput forwards a command's raw stdout/stderr, for views that
+	// still want the unprocessed stream (stream is "stdout" or "stderr").
+	// Run calls it once per stream with that stream's whole captured
+	// output, not line by line.
+	RawOutput(stream string, line string)
+}
+
+// PlanSummary is the structured result of classifying a plan, replacing
+// the previous "does stdout contain these magic strings" checks.
+type PlanSummary struct {
+	Dir   string          `json:"dir"`
+	Class PlanChangeClass `json:"class"`
+	Empty bool            `json:"empty"`
+}
+
+// Diagnostic is a single diagnostic message surfaced by a View.
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// HumanView is the default View. It forwards a command's stdout/stderr
+// and diagnostics as plain text to its writer and leaves progress
+// reporting to the terraform command itself. With a nil writer, as
+// NewTerraformCLI sets it up, it writes nothing.
+type HumanView struct {
+	out io.Writer
+}
+
+// NewHumanView returns a HumanView that forwards raw output to out; a nil
+// out discards it.
+func NewHumanView(out io.Writer) *HumanView {
+	return &HumanView{out: out}
+}
+
+// PlanStarted implements View.
+func (v *HumanView) PlanStarted(dir string) {}
+
+// PlanFinished implements View.
+func (v *HumanView) PlanFinished(summary PlanSummary) {}
+
+// Diagnostic implements View.
+func (v *HumanView) Diagnostic(d Diagnostic) {
+	if v.out == nil {
+		return
+	}
+	fmt.Fprintf(v.out, "%s: %s\n", d.Severity, d.Summary)
+}
+
+// RawOutput implements View.
+func (v *HumanView) RawOutput(stream string, line string) {
+	if v.out == nil {
+		return
+	}
+	fmt.Fprintln(v.out, line)
+}
+
+// jsonViewEvent is the envelope written by JSONView, one per line.
+type jsonViewEvent struct {
+	Type    string       `json:"type"`
+	Dir     string       `json:"dir,omitempty"`
+	Summary *PlanSummary `json:"summary,omitempty"`
+	Diag    *Diagnostic  `json:"diagnostic,omitempty"`
+	Stream  string       `json:"stream,omitempty"`
+	Line    string       `json:"line,omitempty"`
+}
+
+// JSONView emits newline-delimited JSON events suitable for a CI system
+// or a tool rendering migration progress structurally, instead of
+// scraping terraform's human-readable output.
+type JSONView struct {
+	enc *json.Encoder
+}
+
+// NewJSONView returns a JSONView that writes one JSON event per line to out.
+func NewJSONView(out io.Writer) *JSONView {
+	return &JSONView{enc: json.NewEncoder(out)}
+}
+
+// PlanStarted implements View.
+func (v *JSONView) PlanStarted(dir string) {
+	v.emit(jsonViewEvent{Type: "plan_started", Dir: dir})
+}
+
+// PlanFinished implements View.
+func (v *JSONView) PlanFinished(summary PlanSummary) {
+	v.emit(jsonViewEvent{Type: "plan_finished", Summary: &summary})
+}
+
+// Diagnostic implements View.
+func (v *JSONView) Diagnostic(d Diagnostic) {
+	v.emit(jsonViewEvent{Type: "diagnostic", Diag: &d})
+}
+
+// RawOutput implements View.
+func (v *JSONView) RawOutput(stream string, line string) {
+	v.emit(jsonViewEvent{Type: "raw_output", Stream: stream, Line: line})
+}
+
+func (v *JSONView) emit(e jsonViewEvent) {
+	// Best-effort: a view is for observability, so a failure to encode an
+	// event shouldn't fail the migration itself.
+	_ = v.enc.Encode(e)
+}
@@ -0,0 +1,112 @@
+package tfexec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONViewEmitsOneEventPerLine(t *testing.T) {
+	var buf bytes.Buffer
+	v := NewJSONView(&buf)
+
+	v.PlanStarted("/path/to/dir")
+	v.PlanFinished(PlanSummary{Class: PlanChangeOutputOnly})
+	v.Diagnostic(Diagnostic{Severity: "warning", Summary: "deprecated argument"})
+	v.RawOutput("stdout", "Refreshing state...")
+
+	dec := json.NewDecoder(&buf)
+	var gotTypes []string
+	for dec.More() {
+		var e jsonViewEvent
+		if err := dec.Decode(&e); err != nil {
+			t.Fatalf("failed to decode event: %s", err)
+		}
+		gotTypes = append(gotTypes, e.Type)
+	}
+
+	want := []string{"plan_started", "plan_finished", "diagnostic", "raw_output"}
+	if len(gotTypes) != len(want) {
+		t.Fatalf("got %d events, want %d: %v", len(gotTypes), len(want), gotTypes)
+	}
+	for i, w := range want {
+		if gotTypes[i] != w {
+			t.Errorf("event %d: got %s, want %s", i, gotTypes[i], w)
+		}
+	}
+}
+
+// fakeExecutor is a minimal Executor used to test terraformCLI behavior
+// without depending on the package's mock command infrastructure. If
+// runFunc is set, it's used to compute each Run call's result; otherwise
+// every call returns the fixed stdout/stderr/err fields.
+type fakeExecutor struct {
+	dir            string
+	stdout, stderr string
+	err            error
+	runFunc        func(args ...string) (string, string, error)
+}
+
+func (e *fakeExecutor) Dir() string { return e.dir }
+
+func (e *fakeExecutor) Run(ctx context.Context, args ...string) (string, string, error) {
+	if e.runFunc != nil {
+		return e.runFunc(args...)
+	}
+	return e.stdout, e.stderr, e.err
+}
+
+// fakeView records every RawOutput call made to it (its other methods do
+// nothing), so tests can assert Run actually reports to the View it was
+// configured with.
+type fakeView struct {
+	rawOutputs []string
+}
+
+func (v *fakeView) PlanStarted(dir string)    {}
+func (v *fakeView) PlanFinished(s PlanSummary) {}
+func (v *fakeView) Diagnostic(d Diagnostic)   {}
+
+func (v *fakeView) RawOutput(stream string, line string) {
+	v.rawOutputs = append(v.rawOutputs, stream+":"+line)
+}
+
+func TestNewTerraformCLIDefaultsToHumanView(t *testing.T) {
+	terraformCLI := NewTerraformCLI(&fakeExecutor{})
+	if _, ok := terraformCLI.view.(*HumanView); !ok {
+		t.Errorf("got %T, want *HumanView", terraformCLI.view)
+	}
+}
+
+func TestTerraformCLIRunReportsRawOutputToView(t *testing.T) {
+	e := &fakeExecutor{stdout: "plan output", stderr: "a warning"}
+	terraformCLI := NewTerraformCLI(e)
+
+	v := &fakeView{}
+	terraformCLI.SetView(v)
+
+	if _, _, err := terraformCLI.Run(context.Background(), "plan"); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	want := []string{"stdout:plan output", "stderr:a warning"}
+	if len(v.rawOutputs) != len(want) {
+		t.Fatalf("got %d raw outputs, want %d: %v", len(v.rawOutputs), len(want), v.rawOutputs)
+	}
+	for i, w := range want {
+		if v.rawOutputs[i] != w {
+			t.Errorf("raw output %d: got %s, want %s", i, v.rawOutputs[i], w)
+		}
+	}
+}
+
+func TestTerraformCLISetViewNilRestoresHumanView(t *testing.T) {
+	terraformCLI := NewTerraformCLI(&fakeExecutor{})
+	terraformCLI.SetView(&fakeView{})
+	terraformCLI.SetView(nil)
+
+	if _, ok := terraformCLI.view.(*HumanView); !ok {
+		t.Errorf("got %T, want *HumanView", terraformCLI.view)
+	}
+}